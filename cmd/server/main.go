@@ -0,0 +1,32 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os/signal"
+	"syscall"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"shorty/internal/config"
+	httpapi "shorty/internal/http"
+)
+
+func main() {
+	cfg := config.Load()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	pool, err := pgxpool.New(ctx, cfg.DatabaseUrl)
+	if err != nil {
+		log.Fatalf("connecting to database: %v", err)
+	}
+
+	srv := httpapi.NewServer(pool, httpapi.DefaultServerConfig(":"+cfg.Port, cfg.BaseUrl))
+
+	log.Printf("listening on :%s", cfg.Port)
+	if err := srv.Run(ctx); err != nil {
+		log.Fatalf("server error: %v", err)
+	}
+}
@@ -0,0 +1,194 @@
+package httpapi
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+
+	db "shorty/internal/db/sqlc"
+)
+
+const authUserContextKey = "authUser"
+
+// authUser is the identity attached to the gin context by requireAuth.
+type authUser struct {
+	ID      int64
+	Email   string
+	IsAdmin bool
+}
+
+type registerIn struct {
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required,min=8"`
+}
+
+type loginIn struct {
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required"`
+}
+
+type authOut struct {
+	Token string `json:"token"`
+	User  struct {
+		ID      int64  `json:"id"`
+		Email   string `json:"email"`
+		IsAdmin bool   `json:"is_admin"`
+	} `json:"user"`
+}
+
+// requireAuth authenticates the `Authorization: Bearer <token>` header
+// against api_tokens, rejecting revoked or unknown tokens, and attaches the
+// owning user to the request context for downstream handlers.
+func (h *Handler) requireAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader("Authorization")
+		raw, ok := strings.CutPrefix(header, "Bearer ")
+		raw = strings.TrimSpace(raw)
+		if !ok || raw == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing bearer token"})
+			return
+		}
+
+		ctx := c.Request.Context()
+
+		tok, err := h.Q.GetAPITokenByHash(ctx, hashToken(raw))
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+			return
+		}
+
+		if tok.RevokedAt.Valid {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "token revoked"})
+			return
+		}
+
+		_, _ = h.Q.TouchAPIToken(ctx, tok.ID)
+
+		c.Set(authUserContextKey, authUser{ID: tok.UserID, Email: tok.Email, IsAdmin: tok.IsAdmin})
+		c.Next()
+	}
+}
+
+// currentUser returns the authenticated user set by requireAuth. It must
+// only be called from handlers mounted behind that middleware.
+func currentUser(c *gin.Context) authUser {
+	u, _ := c.Get(authUserContextKey)
+	au, _ := u.(authUser)
+	return au
+}
+
+func (h *Handler) register(c *gin.Context) {
+	var in registerIn
+	if err := c.ShouldBindJSON(&in); err != nil {
+		writeBindError(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(in.Password), bcrypt.DefaultCost)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to hash password"})
+		return
+	}
+
+	user, err := h.Q.CreateUser(ctx, db.CreateUserParams{
+		Email:        strings.ToLower(strings.TrimSpace(in.Email)),
+		PasswordHash: string(passwordHash),
+	})
+	if err != nil {
+		if isUniqueViolation(err) {
+			c.JSON(http.StatusConflict, gin.H{"error": "email already registered"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		return
+	}
+
+	h.respondWithNewToken(c, user.ID, user.Email, user.IsAdmin, http.StatusCreated)
+}
+
+func (h *Handler) login(c *gin.Context) {
+	var in loginIn
+	if err := c.ShouldBindJSON(&in); err != nil {
+		writeBindError(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	user, err := h.Q.GetUserByEmail(ctx, strings.ToLower(strings.TrimSpace(in.Email)))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		return
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(in.Password)); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
+		return
+	}
+
+	h.respondWithNewToken(c, user.ID, user.Email, user.IsAdmin, http.StatusOK)
+}
+
+func (h *Handler) respondWithNewToken(c *gin.Context, userID int64, email string, isAdmin bool, status int) {
+	raw := randomBase62(40)
+
+	if _, err := h.Q.CreateAPIToken(c.Request.Context(), db.CreateAPITokenParams{
+		UserID:   userID,
+		TokenSha: hashToken(raw),
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		return
+	}
+
+	out := authOut{Token: raw}
+	out.User.ID = userID
+	out.User.Email = email
+	out.User.IsAdmin = isAdmin
+
+	c.JSON(status, out)
+}
+
+func (h *Handler) revokeToken(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+
+	user := currentUser(c)
+
+	n, err := h.Q.RevokeAPIToken(c.Request.Context(), db.RevokeAPITokenParams{
+		ID:     id,
+		UserID: user.ID,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		return
+	}
+	if n == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func hashToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,262 @@
+package httpapi
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	qrcode "github.com/skip2/go-qrcode"
+	xdraw "golang.org/x/image/draw"
+)
+
+type qrOptions struct {
+	Size       int
+	Level      qrcode.RecoveryLevel
+	Foreground string
+	Background string
+}
+
+func defaultQROptions() qrOptions {
+	return qrOptions{Size: 256, Level: qrcode.Medium, Foreground: "#000000", Background: "#ffffff"}
+}
+
+// parseQROptions reads ?size=, ?level=(L|M|Q|H), ?fg=, ?bg= into qrOptions,
+// validating each against sane bounds.
+func parseQROptions(c *gin.Context) (qrOptions, error) {
+	opts := defaultQROptions()
+
+	if raw := c.Query("size"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 32 || n > 2048 {
+			return opts, errors.New("size must be between 32 and 2048")
+		}
+		opts.Size = n
+	}
+
+	if raw := c.Query("level"); raw != "" {
+		switch strings.ToUpper(raw) {
+		case "L":
+			opts.Level = qrcode.Low
+		case "M":
+			opts.Level = qrcode.Medium
+		case "Q":
+			opts.Level = qrcode.High
+		case "H":
+			opts.Level = qrcode.Highest
+		default:
+			return opts, errors.New("level must be one of L, M, Q, H")
+		}
+	}
+
+	if raw := c.Query("fg"); raw != "" {
+		if !isHexColor(raw) {
+			return opts, errors.New("fg must be a #rrggbb color")
+		}
+		opts.Foreground = raw
+	}
+	if raw := c.Query("bg"); raw != "" {
+		if !isHexColor(raw) {
+			return opts, errors.New("bg must be a #rrggbb color")
+		}
+		opts.Background = raw
+	}
+
+	return opts, nil
+}
+
+func isHexColor(s string) bool {
+	s = strings.TrimPrefix(s, "#")
+	if len(s) != 6 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
+
+func parseRGBA(s string) (color.RGBA, error) {
+	s = strings.TrimPrefix(s, "#")
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) != 3 {
+		return color.RGBA{}, fmt.Errorf("invalid color %q", s)
+	}
+	return color.RGBA{R: b[0], G: b[1], B: b[2], A: 255}, nil
+}
+
+// overlayLogo composites logo, scaled to roughly a quarter of base's width,
+// centered on top of the rendered QR code.
+func overlayLogo(base image.Image, logo image.Image) image.Image {
+	bounds := base.Bounds()
+	out := image.NewRGBA(bounds)
+	draw.Draw(out, bounds, base, image.Point{}, draw.Src)
+
+	logoSize := bounds.Dx() / 4
+	offset := image.Pt((bounds.Dx()-logoSize)/2, (bounds.Dy()-logoSize)/2)
+	dstRect := image.Rect(0, 0, logoSize, logoSize).Add(offset)
+	xdraw.CatmullRom.Scale(out, dstRect, logo, logo.Bounds(), xdraw.Over, nil)
+
+	return out
+}
+
+// qrCacheMaxEntries bounds qrCache's footprint: size/fg/bg/logo are all
+// attacker-controlled via the public /r/:code.qr endpoint, so the keyspace
+// is effectively unbounded and the cache must evict rather than grow
+// forever.
+const qrCacheMaxEntries = 512
+
+// qrCache memoizes rendered PNGs in memory, keyed by link id and the
+// options (including an optional logo) that produced them, so repeat
+// requests for the same short link/size/logo skip re-encoding. Once it
+// reaches qrCacheMaxEntries, the oldest entry is evicted to make room.
+type qrCache struct {
+	mu    sync.Mutex
+	byKey map[string][]byte
+	order []string
+}
+
+func newQRCache() *qrCache {
+	return &qrCache{byKey: make(map[string][]byte)}
+}
+
+func qrCacheKey(linkID int64, opts qrOptions, logoHash string) string {
+	return fmt.Sprintf("%d:%d:%d:%s:%s:%s", linkID, opts.Size, opts.Level, opts.Foreground, opts.Background, logoHash)
+}
+
+func (c *qrCache) get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	b, ok := c.byKey[key]
+	return b, ok
+}
+
+func (c *qrCache) put(key string, png []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, exists := c.byKey[key]; !exists {
+		c.order = append(c.order, key)
+	}
+	c.byKey[key] = png
+
+	for len(c.order) > qrCacheMaxEntries {
+		oldest := c.order[0]
+		c.order = c.order[1:]
+		delete(c.byKey, oldest)
+	}
+}
+
+// renderQR encodes target into a PNG QR code per opts, optionally
+// compositing logo centered on top of it.
+func renderQR(target string, opts qrOptions, logo image.Image) ([]byte, error) {
+	q, err := qrcode.New(target, opts.Level)
+	if err != nil {
+		return nil, err
+	}
+
+	fg, _ := parseRGBA(opts.Foreground)
+	bg, _ := parseRGBA(opts.Background)
+	q.ForegroundColor = fg
+	q.BackgroundColor = bg
+
+	img := q.Image(opts.Size)
+	if logo != nil {
+		img = overlayLogo(img, logo)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (h *Handler) linkQR(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+
+	row, err := h.Q.GetLink(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		return
+	}
+
+	user := currentUser(c)
+	if !user.IsAdmin && row.OwnerID != user.ID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+
+	h.writeQR(c, row.ID, h.shortURL(row.ShortName))
+}
+
+// writeQR renders (or serves from cache) a PNG QR code pointing at
+// targetURL, honoring the size/level/fg/bg query params and an optional
+// multipart "logo" file to overlay.
+func (h *Handler) writeQR(c *gin.Context, linkID int64, targetURL string) {
+	opts, err := parseQROptions(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var logo image.Image
+	var logoHash string
+	if file, ferr := c.FormFile("logo"); ferr == nil {
+		f, err := file.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid logo"})
+			return
+		}
+		defer f.Close()
+
+		data, err := io.ReadAll(f)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid logo"})
+			return
+		}
+
+		img, _, err := image.Decode(bytes.NewReader(data))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported logo image"})
+			return
+		}
+		logo = img
+
+		sum := sha256.Sum256(data)
+		logoHash = hex.EncodeToString(sum[:])
+	}
+
+	key := qrCacheKey(linkID, opts, logoHash)
+	if cached, ok := h.QRCache.get(key); ok {
+		c.Data(http.StatusOK, "image/png", cached)
+		return
+	}
+
+	rendered, err := renderQR(targetURL, opts, logo)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to render qr code"})
+		return
+	}
+
+	h.QRCache.put(key, rendered)
+	c.Data(http.StatusOK, "image/png", rendered)
+}
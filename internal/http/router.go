@@ -1,6 +1,7 @@
 package httpapi
 
 import (
+	"context"
 	"crypto/rand"
 	"database/sql"
 	"encoding/json"
@@ -18,46 +19,91 @@ import (
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
 
 	db "shorty/internal/db/sqlc"
 )
 
 type Handler struct {
-	Q       *db.Queries
-	BaseURL string
+	Q           *db.Queries
+	BaseURL     string
+	Recorder    *VisitRecorder
+	Broadcaster *visitBroadcaster
+	QRCache     *qrCache
 }
 
 type linkIn struct {
-	OriginalURL string `json:"original_url" binding:"required,url"`
-	ShortName   string `json:"short_name" binding:"omitempty,shortname"`
+	OriginalURL string     `json:"original_url" binding:"required,url"`
+	ShortName   string     `json:"short_name" binding:"omitempty,shortname"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	MaxClicks   *int32     `json:"max_clicks" binding:"omitempty,min=1"`
 }
 
 type linkOut struct {
-	ID          int64  `json:"id"`
-	OriginalURL string `json:"original_url"`
-	ShortName   string `json:"short_name"`
-	ShortURL    string `json:"short_url"`
+	ID          int64      `json:"id"`
+	OriginalURL string     `json:"original_url"`
+	ShortName   string     `json:"short_name"`
+	ShortURL    string     `json:"short_url"`
+	OwnerID     int64      `json:"owner_id"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	MaxClicks   *int32     `json:"max_clicks,omitempty"`
+	ClickCount  int32      `json:"click_count"`
+	Active      bool       `json:"active"`
 }
 
 type linkVisitOut struct {
-	ID        int64     `json:"id"`
-	LinkID    int64     `json:"link_id"`
-	CreatedAt time.Time `json:"created_at"`
-	IP        string    `json:"ip"`
-	UserAgent string    `json:"user_agent"`
-	Status    int32     `json:"status"`
+	ID             int64     `json:"id"`
+	LinkID         int64     `json:"link_id"`
+	CreatedAt      time.Time `json:"created_at"`
+	IP             string    `json:"ip"`
+	UserAgent      string    `json:"user_agent"`
+	Status         int32     `json:"status"`
+	Browser        string    `json:"browser"`
+	BrowserVersion string    `json:"browser_version"`
+	OS             string    `json:"os"`
+	DeviceType     string    `json:"device_type"`
+	IsBot          bool      `json:"is_bot"`
+}
+
+type statCountOut struct {
+	Name  string `json:"name"`
+	Count int64  `json:"count"`
 }
 
 var shortNameRe = regexp.MustCompile(`^[a-zA-Z0-9_-]{3,32}$`)
 
-func NewRouter(q *db.Queries, baseURL string) *gin.Engine {
+// NewRouter builds a ready-to-serve engine with its own background
+// VisitRecorder and expiry sweeper, both running for the lifetime of the
+// process. Callers that need coordinated shutdown (flushing the recorder,
+// closing the pool) should use Server instead.
+func NewRouter(q *db.Queries, pool *pgxpool.Pool, baseURL string) *gin.Engine {
 	setupValidator()
 
+	recorder := NewVisitRecorder(pool, DefaultVisitRecorderConfig())
+	broadcaster := newVisitBroadcaster()
+	recorder.OnFlush = func(rows []db.LinkVisit) {
+		for _, row := range rows {
+			broadcaster.publish(visitOutFromRow(row))
+		}
+	}
+	recorder.Start(context.Background())
+
 	h := &Handler{
-		Q:       q,
-		BaseURL: strings.TrimRight(baseURL, "/"),
+		Q:           q,
+		BaseURL:     strings.TrimRight(baseURL, "/"),
+		Recorder:    recorder,
+		Broadcaster: broadcaster,
+		QRCache:     newQRCache(),
 	}
 
+	go h.sweepExpiredLinks(context.Background())
+
+	return newEngine(h)
+}
+
+// newEngine wires up middleware and routes for h. It does not start any
+// background goroutines -- callers own that so they can control shutdown.
+func newEngine(h *Handler) *gin.Engine {
 	r := gin.New()
 
 	r.TrustedPlatform = gin.PlatformCloudflare
@@ -93,28 +139,88 @@ func NewRouter(q *db.Queries, baseURL string) *gin.Engine {
 
 	r.GET("/r/:code", h.redirectByCode)
 
+	auth := r.Group("/auth")
+	{
+		auth.POST("/register", h.register)
+		auth.POST("/login", h.login)
+		auth.DELETE("/tokens/:id", h.requireAuth(), h.revokeToken)
+	}
+
 	api := r.Group("/api")
+	api.Use(h.requireAuth())
 	{
 		api.GET("/links", h.listLinks)
 		api.POST("/links", h.createLink)
 		api.GET("/links/:id", h.getLink)
 		api.PUT("/links/:id", h.updateLink)
 		api.DELETE("/links/:id", h.deleteLink)
+		api.GET("/links/:id/qr", h.linkQR)
 
 		api.GET("/link_visits", h.listLinkVisits)
+		api.GET("/link_visits/stream", h.streamLinkVisits)
+
+		api.GET("/stats/links/:id/browsers", h.linkBrowserStats)
+		api.GET("/stats/links/:id/devices", h.linkDeviceStats)
 	}
 
 	return r
 }
 
+// sweepExpiredLinks periodically marks links inactive once they've passed
+// their expires_at or max_clicks budget, so listLinks?active=true doesn't
+// have to recompute expiry on every read.
+func (h *Handler) sweepExpiredLinks(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if _, err := h.Q.DeactivateExpiredLinks(ctx); err != nil {
+			continue
+		}
+	}
+}
+
+// computeActive derives the active flag a link update should persist, using
+// the same expires_at/max_clicks criteria as sweepExpiredLinks's
+// DeactivateExpiredLinks. Recomputing it on every update (rather than only
+// ever clearing it) lets extending a swept link's expiry or click budget
+// re-activate it instead of leaving it permanently expired.
+func computeActive(expiresAt sql.NullTime, maxClicks sql.NullInt32, clickCount int32) bool {
+	if expiresAt.Valid && time.Now().After(expiresAt.Time) {
+		return false
+	}
+	if maxClicks.Valid && clickCount >= maxClicks.Int32 {
+		return false
+	}
+	return true
+}
+
 func (h *Handler) shortURL(shortName string) string {
 	return h.BaseURL + "/r/" + shortName
 }
 
+// linksOwnerFilter returns the owner_id a links query should be scoped to,
+// and whether the caller may see links owned by anyone. Admins see every
+// link unless they opt into a narrower view with ?owner_id=.
+func linksOwnerFilter(c *gin.Context, user authUser) (ownerID int64, allOwners bool) {
+	if !user.IsAdmin {
+		return user.ID, false
+	}
+	if raw := c.Query("owner_id"); raw != "" {
+		if id, err := strconv.ParseInt(raw, 10, 64); err == nil {
+			return id, false
+		}
+	}
+	return 0, true
+}
+
 func (h *Handler) listLinks(c *gin.Context) {
 	ctx := c.Request.Context()
+	user := currentUser(c)
+	ownerID, allOwners := linksOwnerFilter(c, user)
+	activeOnly := c.Query("active") == "true"
 
-	total, err := h.Q.CountLinks(ctx)
+	total, err := h.countLinks(ctx, ownerID, allOwners, activeOnly)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
 		return
@@ -123,24 +229,14 @@ func (h *Handler) listLinks(c *gin.Context) {
 	rawRange := c.Query("range")
 
 	if strings.TrimSpace(rawRange) == "" {
-		rows, err := h.Q.ListLinks(ctx)
+		rows, err := h.listLinksAll(ctx, ownerID, allOwners, activeOnly)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
 			return
 		}
 
-		out := make([]linkOut, 0, len(rows))
-		for _, r := range rows {
-			out = append(out, linkOut{
-				ID:          r.ID,
-				OriginalURL: r.OriginalUrl,
-				ShortName:   r.ShortName,
-				ShortURL:    h.shortURL(r.ShortName),
-			})
-		}
-
-		setContentRange(c, "links", 0, len(out), total)
-		c.JSON(http.StatusOK, out)
+		setContentRange(c, "links", 0, len(rows), total)
+		c.JSON(http.StatusOK, rows)
 		return
 	}
 
@@ -168,27 +264,126 @@ func (h *Handler) listLinks(c *gin.Context) {
 		return
 	}
 
-	rows, err := h.Q.ListLinksRange(ctx, db.ListLinksRangeParams{
-		Limit:  int32(limit),
-		Offset: int32(from),
-	})
+	rows, err := h.listLinksRange(ctx, ownerID, allOwners, activeOnly, limit, from)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
 		return
 	}
 
+	setContentRange(c, "links", from, len(rows), total)
+	c.JSON(http.StatusOK, rows)
+}
+
+func (h *Handler) countLinks(ctx context.Context, ownerID int64, allOwners, activeOnly bool) (int64, error) {
+	switch {
+	case allOwners && activeOnly:
+		return h.Q.CountActiveLinks(ctx)
+	case allOwners:
+		return h.Q.CountLinks(ctx)
+	case activeOnly:
+		return h.Q.CountActiveLinksByOwner(ctx, ownerID)
+	default:
+		return h.Q.CountLinksByOwner(ctx, ownerID)
+	}
+}
+
+func (h *Handler) listLinksAll(ctx context.Context, ownerID int64, allOwners, activeOnly bool) ([]linkOut, error) {
+	var (
+		rows []db.Link
+		err  error
+	)
+	switch {
+	case allOwners && activeOnly:
+		rows, err = h.Q.ListActiveLinks(ctx)
+	case allOwners:
+		rows, err = h.Q.ListLinks(ctx)
+	case activeOnly:
+		rows, err = h.Q.ListActiveLinksByOwner(ctx, ownerID)
+	default:
+		rows, err = h.Q.ListLinksByOwner(ctx, ownerID)
+	}
+	if err != nil {
+		return nil, err
+	}
 	out := make([]linkOut, 0, len(rows))
 	for _, r := range rows {
-		out = append(out, linkOut{
-			ID:          r.ID,
-			OriginalURL: r.OriginalUrl,
-			ShortName:   r.ShortName,
-			ShortURL:    h.shortURL(r.ShortName),
+		out = append(out, h.toLinkOut(r))
+	}
+	return out, nil
+}
+
+func (h *Handler) listLinksRange(ctx context.Context, ownerID int64, allOwners, activeOnly bool, limit, from int) ([]linkOut, error) {
+	var (
+		rows []db.Link
+		err  error
+	)
+	switch {
+	case allOwners && activeOnly:
+		rows, err = h.Q.ListActiveLinksRange(ctx, db.ListActiveLinksRangeParams{
+			Limit:  int32(limit),
+			Offset: int32(from),
+		})
+	case allOwners:
+		rows, err = h.Q.ListLinksRange(ctx, db.ListLinksRangeParams{
+			Limit:  int32(limit),
+			Offset: int32(from),
+		})
+	case activeOnly:
+		rows, err = h.Q.ListActiveLinksRangeByOwner(ctx, db.ListActiveLinksRangeByOwnerParams{
+			OwnerID: ownerID,
+			Limit:   int32(limit),
+			Offset:  int32(from),
 		})
+	default:
+		rows, err = h.Q.ListLinksRangeByOwner(ctx, db.ListLinksRangeByOwnerParams{
+			OwnerID: ownerID,
+			Limit:   int32(limit),
+			Offset:  int32(from),
+		})
+	}
+	if err != nil {
+		return nil, err
 	}
+	out := make([]linkOut, 0, len(rows))
+	for _, r := range rows {
+		out = append(out, h.toLinkOut(r))
+	}
+	return out, nil
+}
 
-	setContentRange(c, "links", from, len(out), total)
-	c.JSON(http.StatusOK, out)
+func (h *Handler) toLinkOut(row db.Link) linkOut {
+	out := linkOut{
+		ID:          row.ID,
+		OriginalURL: row.OriginalUrl,
+		ShortName:   row.ShortName,
+		ShortURL:    h.shortURL(row.ShortName),
+		OwnerID:     row.OwnerID,
+		ClickCount:  row.ClickCount,
+		Active:      row.Active,
+	}
+	if row.ExpiresAt.Valid {
+		t := row.ExpiresAt.Time.UTC()
+		out.ExpiresAt = &t
+	}
+	if row.MaxClicks.Valid {
+		mc := row.MaxClicks.Int32
+		out.MaxClicks = &mc
+	}
+	return out
+}
+
+func toNullTime(t *time.Time) sql.NullTime {
+	if t == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: *t, Valid: true}
+}
+
+func toNullInt32(n *int32) sql.NullInt32 {
+	if n == nil {
+		return sql.NullInt32{}
+	}
+	return sql.NullInt32{Int32: *n, Valid: true}
 }
 
 func (h *Handler) createLink(c *gin.Context) {
@@ -199,12 +394,18 @@ func (h *Handler) createLink(c *gin.Context) {
 	}
 
 	ctx := c.Request.Context()
+	ownerID := currentUser(c).ID
+	expiresAt := toNullTime(in.ExpiresAt)
+	maxClicks := toNullInt32(in.MaxClicks)
 
 	shortName := strings.TrimSpace(in.ShortName)
 	if shortName != "" {
 		row, err := h.Q.CreateLink(ctx, db.CreateLinkParams{
 			OriginalUrl: in.OriginalURL,
 			ShortName:   shortName,
+			OwnerID:     ownerID,
+			ExpiresAt:   expiresAt,
+			MaxClicks:   maxClicks,
 		})
 		if err != nil {
 			if isUniqueViolation(err) {
@@ -215,12 +416,7 @@ func (h *Handler) createLink(c *gin.Context) {
 			return
 		}
 
-		c.JSON(http.StatusCreated, linkOut{
-			ID:          row.ID,
-			OriginalURL: row.OriginalUrl,
-			ShortName:   row.ShortName,
-			ShortURL:    h.shortURL(row.ShortName),
-		})
+		c.JSON(http.StatusCreated, h.toLinkOut(row))
 		return
 	}
 
@@ -229,6 +425,9 @@ func (h *Handler) createLink(c *gin.Context) {
 		row, err := h.Q.CreateLink(ctx, db.CreateLinkParams{
 			OriginalUrl: in.OriginalURL,
 			ShortName:   gen,
+			OwnerID:     ownerID,
+			ExpiresAt:   expiresAt,
+			MaxClicks:   maxClicks,
 		})
 		if err != nil {
 			if isUniqueViolation(err) {
@@ -238,12 +437,7 @@ func (h *Handler) createLink(c *gin.Context) {
 			return
 		}
 
-		c.JSON(http.StatusCreated, linkOut{
-			ID:          row.ID,
-			OriginalURL: row.OriginalUrl,
-			ShortName:   row.ShortName,
-			ShortURL:    h.shortURL(row.ShortName),
-		})
+		c.JSON(http.StatusCreated, h.toLinkOut(row))
 		return
 	}
 
@@ -266,12 +460,13 @@ func (h *Handler) getLink(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, linkOut{
-		ID:          row.ID,
-		OriginalURL: row.OriginalUrl,
-		ShortName:   row.ShortName,
-		ShortURL:    h.shortURL(row.ShortName),
-	})
+	user := currentUser(c)
+	if !user.IsAdmin && row.OwnerID != user.ID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, h.toLinkOut(row))
 }
 
 func (h *Handler) updateLink(c *gin.Context) {
@@ -289,23 +484,36 @@ func (h *Handler) updateLink(c *gin.Context) {
 	ctx := c.Request.Context()
 
 	shortName := strings.TrimSpace(in.ShortName)
-	if shortName == "" {
-		existing, err := h.Q.GetLink(ctx, id)
-		if err != nil {
-			if errors.Is(err, sql.ErrNoRows) {
-				c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
-				return
-			}
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+	user := currentUser(c)
+
+	existing, err := h.Q.GetLink(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
 			return
 		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		return
+	}
+	if !user.IsAdmin && existing.OwnerID != user.ID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+
+	if shortName == "" {
 		shortName = existing.ShortName
 	}
 
+	expiresAt := toNullTime(in.ExpiresAt)
+	maxClicks := toNullInt32(in.MaxClicks)
+
 	row, err := h.Q.UpdateLink(ctx, db.UpdateLinkParams{
 		ID:          id,
 		OriginalUrl: in.OriginalURL,
 		ShortName:   shortName,
+		ExpiresAt:   expiresAt,
+		MaxClicks:   maxClicks,
+		Active:      computeActive(expiresAt, maxClicks, existing.ClickCount),
 	})
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -320,12 +528,7 @@ func (h *Handler) updateLink(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, linkOut{
-		ID:          row.ID,
-		OriginalURL: row.OriginalUrl,
-		ShortName:   row.ShortName,
-		ShortURL:    h.shortURL(row.ShortName),
-	})
+	c.JSON(http.StatusOK, h.toLinkOut(row))
 }
 
 func (h *Handler) deleteLink(c *gin.Context) {
@@ -334,6 +537,22 @@ func (h *Handler) deleteLink(c *gin.Context) {
 		return
 	}
 
+	user := currentUser(c)
+
+	existing, err := h.Q.GetLink(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		return
+	}
+	if !user.IsAdmin && existing.OwnerID != user.ID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+
 	n, err := h.Q.DeleteLink(c.Request.Context(), id)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
@@ -349,6 +568,8 @@ func (h *Handler) deleteLink(c *gin.Context) {
 
 func (h *Handler) redirectByCode(c *gin.Context) {
 	code := strings.TrimSpace(c.Param("code"))
+	asQR := strings.HasSuffix(code, ".qr")
+	code = strings.TrimSuffix(code, ".qr")
 	if code == "" {
 		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
 		return
@@ -364,27 +585,54 @@ func (h *Handler) redirectByCode(c *gin.Context) {
 		return
 	}
 
+	if asQR {
+		h.writeQR(c, row.ID, h.shortURL(row.ShortName))
+		return
+	}
+
 	status := http.StatusFound
+	// Recomputed live rather than trusting row.Active: the sweeper only
+	// refreshes that cached flag once a minute, and updateLink may not have
+	// run since expires_at/max_clicks last changed.
+	expired := !computeActive(row.ExpiresAt, row.MaxClicks, row.ClickCount)
+	if expired {
+		status = http.StatusGone
+	}
 
 	ip := c.ClientIP()
 	ua := c.GetHeader("User-Agent")
 	ref := c.GetHeader("Referer")
-
-	_, _ = h.Q.CreateLinkVisit(c.Request.Context(), db.CreateLinkVisitParams{
-		LinkID:    row.ID,
-		Ip:        ip,
-		UserAgent: ua,
-		Referer:   ref,
-		Status:    int32(status),
+	agent := parseUserAgent(ua)
+
+	h.Recorder.Enqueue(db.CreateLinkVisitParams{
+		LinkID:         row.ID,
+		Ip:             ip,
+		UserAgent:      ua,
+		Referer:        ref,
+		Status:         int32(status),
+		Browser:        agent.Browser,
+		BrowserVersion: agent.BrowserVersion,
+		Os:             agent.OS,
+		DeviceType:     agent.DeviceType,
+		IsBot:          agent.IsBot,
 	})
 
+	if expired {
+		c.JSON(http.StatusGone, gin.H{"error": "link expired"})
+		return
+	}
+
+	_, _ = h.Q.IncrementLinkClicks(c.Request.Context(), row.ID)
+
 	c.Redirect(status, row.OriginalUrl)
 }
 
 func (h *Handler) listLinkVisits(c *gin.Context) {
 	ctx := c.Request.Context()
+	user := currentUser(c)
+	ownerID, allOwners := linksOwnerFilter(c, user)
 
-	total, err := h.Q.CountLinkVisits(ctx)
+	total, err := h.countLinkVisits(ctx, ownerID, allOwners)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
 		return
@@ -422,10 +670,7 @@ func (h *Handler) listLinkVisits(c *gin.Context) {
 		return
 	}
 
-	rows, err := h.Q.ListLinkVisitsRange(ctx, db.ListLinkVisitsRangeParams{
-		Limit:  int32(limit),
-		Offset: int32(from),
-	})
+	rows, err := h.listLinkVisitsRange(ctx, ownerID, allOwners, limit, from)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
 		return
@@ -434,12 +679,17 @@ func (h *Handler) listLinkVisits(c *gin.Context) {
 	out := make([]linkVisitOut, 0, len(rows))
 	for _, v := range rows {
 		out = append(out, linkVisitOut{
-			ID:        v.ID,
-			LinkID:    v.LinkID,
-			CreatedAt: v.CreatedAt.Time.UTC(),
-			IP:        v.Ip,
-			UserAgent: v.UserAgent,
-			Status:    v.Status,
+			ID:             v.ID,
+			LinkID:         v.LinkID,
+			CreatedAt:      v.CreatedAt.Time.UTC(),
+			IP:             v.Ip,
+			UserAgent:      v.UserAgent,
+			Status:         v.Status,
+			Browser:        v.Browser,
+			BrowserVersion: v.BrowserVersion,
+			OS:             v.Os,
+			DeviceType:     v.DeviceType,
+			IsBot:          v.IsBot,
 		})
 	}
 
@@ -447,6 +697,150 @@ func (h *Handler) listLinkVisits(c *gin.Context) {
 	c.JSON(http.StatusOK, out)
 }
 
+// minStatsTime and maxStatsTime stand in for an omitted ?from=/?to= bound on
+// the aggregation endpoints. statsRange returns these instead of the zero
+// time so the bound stays open regardless of how the underlying query
+// compares it (a zero time against a plain "created_at <= $to" would filter
+// out every row instead of none).
+var (
+	minStatsTime = time.Unix(0, 0).UTC()
+	maxStatsTime = time.Date(9999, 12, 31, 23, 59, 59, 0, time.UTC)
+)
+
+func (h *Handler) countLinkVisits(ctx context.Context, ownerID int64, allOwners bool) (int64, error) {
+	if allOwners {
+		return h.Q.CountLinkVisits(ctx)
+	}
+	return h.Q.CountLinkVisitsByOwner(ctx, ownerID)
+}
+
+func (h *Handler) listLinkVisitsRange(ctx context.Context, ownerID int64, allOwners bool, limit, from int) ([]db.LinkVisit, error) {
+	if allOwners {
+		return h.Q.ListLinkVisitsRange(ctx, db.ListLinkVisitsRangeParams{
+			Limit:  int32(limit),
+			Offset: int32(from),
+		})
+	}
+	return h.Q.ListLinkVisitsRangeByOwner(ctx, db.ListLinkVisitsRangeByOwnerParams{
+		OwnerID: ownerID,
+		Limit:   int32(limit),
+		Offset:  int32(from),
+	})
+}
+
+// statsRange parses the optional ?from=&to= RFC3339 query params used by the
+// aggregation endpoints. Either or both may be omitted, in which case that
+// bound is widened rather than left open-ended at the zero time.
+func statsRange(c *gin.Context) (from, to time.Time, ok bool) {
+	from, to = minStatsTime, maxStatsTime
+
+	if raw := c.Query("from"); raw != "" {
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return from, to, false
+		}
+		from = t
+	}
+	if raw := c.Query("to"); raw != "" {
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return from, to, false
+		}
+		to = t
+	}
+	return from, to, true
+}
+
+// statsLink loads the link id's owning record and 404s if the caller may not
+// see it, mirroring the ownership guard on getLink.
+func (h *Handler) statsLink(c *gin.Context, id int64) (db.Link, bool) {
+	row, err := h.Q.GetLink(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return db.Link{}, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		return db.Link{}, false
+	}
+
+	user := currentUser(c)
+	if !user.IsAdmin && row.OwnerID != user.ID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return db.Link{}, false
+	}
+
+	return row, true
+}
+
+func (h *Handler) linkBrowserStats(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+
+	if _, ok := h.statsLink(c, id); !ok {
+		return
+	}
+
+	from, to, ok := statsRange(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid range"})
+		return
+	}
+
+	rows, err := h.Q.CountLinkVisitsByBrowser(c.Request.Context(), db.CountLinkVisitsByBrowserParams{
+		LinkID: id,
+		From:   from,
+		To:     to,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		return
+	}
+
+	out := make([]statCountOut, 0, len(rows))
+	for _, r := range rows {
+		out = append(out, statCountOut{Name: r.Browser, Count: r.Count})
+	}
+
+	c.JSON(http.StatusOK, out)
+}
+
+func (h *Handler) linkDeviceStats(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+
+	if _, ok := h.statsLink(c, id); !ok {
+		return
+	}
+
+	from, to, ok := statsRange(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid range"})
+		return
+	}
+
+	rows, err := h.Q.CountLinkVisitsByDevice(c.Request.Context(), db.CountLinkVisitsByDeviceParams{
+		LinkID: id,
+		From:   from,
+		To:     to,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		return
+	}
+
+	out := make([]statCountOut, 0, len(rows))
+	for _, r := range rows {
+		out = append(out, statCountOut{Name: r.DeviceType, Count: r.Count})
+	}
+
+	c.JSON(http.StatusOK, out)
+}
+
 func setContentRange(c *gin.Context, resource string, from int, count int, total int64) {
 	if count <= 0 {
 		c.Header("Content-Range", fmt.Sprintf("%s */%d", resource, total))
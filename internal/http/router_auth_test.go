@@ -0,0 +1,94 @@
+package httpapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRegisterLoginAndScopedLinks(t *testing.T) {
+	sqlDB := openSQL(t)
+	defer func() {
+		if err := sqlDB.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	truncateAll(t, sqlDB)
+
+	pool := openPool(t)
+	r := newRouter(t, pool)
+
+	registerBody, _ := json.Marshal(registerIn{Email: "owner@example.com", Password: "hunter2pass"})
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/auth/register", bytes.NewReader(registerBody))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d, body=%s", w.Code, w.Body.String())
+	}
+
+	var registered authOut
+	if err := json.Unmarshal(w.Body.Bytes(), &registered); err != nil {
+		t.Fatal(err)
+	}
+	if registered.Token == "" {
+		t.Fatal("expected a non-empty token")
+	}
+
+	// An unauthenticated request to a protected route is rejected.
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/api/links", nil)
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", w.Code)
+	}
+
+	// Creating a link attributes it to the authenticated user.
+	linkBody, _ := json.Marshal(linkIn{OriginalURL: "https://example.com/owned"})
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPost, "/api/links", bytes.NewReader(linkBody))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+registered.Token)
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d, body=%s", w.Code, w.Body.String())
+	}
+
+	var created linkOut
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatal(err)
+	}
+	if created.OwnerID != registered.User.ID {
+		t.Fatalf("expected owner_id %d, got %d", registered.User.ID, created.OwnerID)
+	}
+
+	// A second user cannot see or fetch the first user's link.
+	secondBody, _ := json.Marshal(registerIn{Email: "other@example.com", Password: "hunter2pass"})
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPost, "/auth/register", bytes.NewReader(secondBody))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	var second authOut
+	if err := json.Unmarshal(w.Body.Bytes(), &second); err != nil {
+		t.Fatal(err)
+	}
+
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/api/links", nil)
+	req.Header.Set("Authorization", "Bearer "+second.Token)
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	var otherLinks []linkOut
+	if err := json.Unmarshal(w.Body.Bytes(), &otherLinks); err != nil {
+		t.Fatal(err)
+	}
+	if len(otherLinks) != 0 {
+		t.Fatalf("expected second user to see no links, got %d", len(otherLinks))
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"path/filepath"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	_ "github.com/jackc/pgx/v5/stdlib"
@@ -120,7 +121,7 @@ func openPool(t *testing.T) *pgxpool.Pool {
 func truncateAll(t *testing.T, sqlDB *sql.DB) {
 	t.Helper()
 
-	_, err := sqlDB.Exec(`TRUNCATE link_visits, links RESTART IDENTITY CASCADE`)
+	_, err := sqlDB.Exec(`TRUNCATE link_visits, links, api_tokens, users RESTART IDENTITY CASCADE`)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -140,11 +141,53 @@ func seedLink(t *testing.T, sqlDB *sql.DB, originalURL, shortName string) int64
 	return id
 }
 
+// seedLinkForOwner is seedLink plus an owner_id, for tests exercising
+// endpoints that scope link_visits to the caller's own links.
+func seedLinkForOwner(t *testing.T, sqlDB *sql.DB, originalURL, shortName string, ownerID int64) int64 {
+	t.Helper()
+
+	var id int64
+	err := sqlDB.QueryRow(
+		`INSERT INTO links (original_url, short_name, owner_id) VALUES ($1, $2, $3) RETURNING id`,
+		originalURL, shortName, ownerID,
+	).Scan(&id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+// seedAuthToken creates a user and a usable bearer token for it, returning
+// the raw token to send in an Authorization header and the user's id.
+func seedAuthToken(t *testing.T, sqlDB *sql.DB) (string, int64) {
+	t.Helper()
+
+	var userID int64
+	err := sqlDB.QueryRow(
+		`INSERT INTO users (email, password_hash) VALUES ($1, $2) RETURNING id`,
+		fmt.Sprintf("test-%s@example.com", randomBase62(8)), "not-a-real-hash",
+	).Scan(&userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	raw := randomBase62(40)
+	_, err = sqlDB.Exec(
+		`INSERT INTO api_tokens (user_id, token_sha) VALUES ($1, $2)`,
+		userID, hashToken(raw),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return raw, userID
+}
+
 func newRouter(t *testing.T, pool *pgxpool.Pool) http.Handler {
 	t.Helper()
 
 	q := db.New(pool)
-	return NewRouter(q, "https://short.io")
+	return NewRouter(q, pool, "https://short.io")
 }
 
 func TestRedirectCreatesVisit(t *testing.T) {
@@ -156,7 +199,8 @@ func TestRedirectCreatesVisit(t *testing.T) {
 	}()
 
 	truncateAll(t, sqlDB)
-	_ = seedLink(t, sqlDB, "https://example.com/long-url", "exmpl")
+	token, userID := seedAuthToken(t, sqlDB)
+	_ = seedLinkForOwner(t, sqlDB, "https://example.com/long-url", "exmpl", userID)
 
 	pool := openPool(t)
 	r := newRouter(t, pool)
@@ -176,9 +220,14 @@ func TestRedirectCreatesVisit(t *testing.T) {
 		t.Fatalf("expected Location %q, got %q", "https://example.com/long-url", loc)
 	}
 
+	// The visit is recorded asynchronously by the VisitRecorder, so give it
+	// time to flush before reading it back.
+	time.Sleep(2 * DefaultVisitRecorderConfig().FlushInterval)
+
 	w = httptest.NewRecorder()
 	req = httptest.NewRequest(http.MethodGet, "/api/link_visits", nil)
 	req.Header.Set("Range", "[0,10]")
+	req.Header.Set("Authorization", "Bearer "+token)
 	r.ServeHTTP(w, req)
 
 	if w.Code != http.StatusOK {
@@ -221,7 +270,8 @@ func TestLinkVisitsPagination(t *testing.T) {
 	}()
 
 	truncateAll(t, sqlDB)
-	linkID := seedLink(t, sqlDB, "https://example.com", "seed")
+	token, userID := seedAuthToken(t, sqlDB)
+	linkID := seedLinkForOwner(t, sqlDB, "https://example.com", "seed", userID)
 
 	for i := 0; i < 12; i++ {
 		_, err := sqlDB.Exec(
@@ -244,6 +294,7 @@ func TestLinkVisitsPagination(t *testing.T) {
 	w := httptest.NewRecorder()
 	req := httptest.NewRequest(http.MethodGet, "/api/link_visits", nil)
 	req.Header.Set("Range", "[0,10]")
+	req.Header.Set("Authorization", "Bearer "+token)
 	r.ServeHTTP(w, req)
 
 	if w.Code != http.StatusOK {
@@ -261,3 +312,50 @@ func TestLinkVisitsPagination(t *testing.T) {
 		t.Fatalf("expected 10 items, got %d", len(page))
 	}
 }
+
+func TestLinkVisitsScopedToOwner(t *testing.T) {
+	sqlDB := openSQL(t)
+	defer func() {
+		if err := sqlDB.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	truncateAll(t, sqlDB)
+	_, ownerID := seedAuthToken(t, sqlDB)
+	linkID := seedLinkForOwner(t, sqlDB, "https://example.com", "seed", ownerID)
+	_, err := sqlDB.Exec(
+		`INSERT INTO link_visits (link_id, ip, user_agent, referer, status) VALUES ($1, $2, $3, $4, $5)`,
+		linkID, "10.0.0.1", "ua", "", 302,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A second user owns no links and must not see the first user's visits.
+	otherToken, _ := seedAuthToken(t, sqlDB)
+
+	pool := openPool(t)
+	r := newRouter(t, pool)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/link_visits", nil)
+	req.Header.Set("Range", "[0,10]")
+	req.Header.Set("Authorization", "Bearer "+otherToken)
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d, body=%s", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("Content-Range"); got != "link_visits */0" {
+		t.Fatalf("expected Content-Range %q, got %q", "link_visits */0", got)
+	}
+
+	var page []map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil {
+		t.Fatal(err)
+	}
+	if len(page) != 0 {
+		t.Fatalf("expected second user to see no visits, got %d", len(page))
+	}
+}
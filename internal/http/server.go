@@ -0,0 +1,109 @@
+package httpapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	db "shorty/internal/db/sqlc"
+)
+
+// ServerConfig controls Server's listen address and graceful shutdown
+// behavior.
+type ServerConfig struct {
+	Addr        string
+	BaseURL     string
+	GracePeriod time.Duration
+}
+
+func DefaultServerConfig(addr, baseURL string) ServerConfig {
+	return ServerConfig{
+		Addr:        addr,
+		BaseURL:     baseURL,
+		GracePeriod: 15 * time.Second,
+	}
+}
+
+// Server owns the full request-serving lifecycle: the pgx pool, the
+// VisitRecorder, and the underlying http.Server. It sequences shutdown so
+// that in-flight requests finish and any buffered visits are flushed before
+// the pool is closed.
+type Server struct {
+	cfg     ServerConfig
+	pool    *pgxpool.Pool
+	handler *Handler
+	http    *http.Server
+}
+
+func NewServer(pool *pgxpool.Pool, cfg ServerConfig) *Server {
+	recorder := NewVisitRecorder(pool, DefaultVisitRecorderConfig())
+	broadcaster := newVisitBroadcaster()
+	recorder.OnFlush = func(rows []db.LinkVisit) {
+		for _, row := range rows {
+			broadcaster.publish(visitOutFromRow(row))
+		}
+	}
+
+	h := &Handler{
+		Q:           db.New(pool),
+		BaseURL:     strings.TrimRight(cfg.BaseURL, "/"),
+		Recorder:    recorder,
+		Broadcaster: broadcaster,
+		QRCache:     newQRCache(),
+	}
+
+	return &Server{
+		cfg:     cfg,
+		pool:    pool,
+		handler: h,
+		http: &http.Server{
+			Addr:    cfg.Addr,
+			Handler: newEngine(h),
+		},
+	}
+}
+
+// Run starts the VisitRecorder, the expiry sweeper, and the HTTP listener,
+// then blocks until ctx is cancelled (e.g. on SIGINT/SIGTERM), at which
+// point it performs a graceful Shutdown. It returns nil on a clean shutdown.
+func (s *Server) Run(ctx context.Context) error {
+	// The recorder is not tied to ctx: Shutdown stops it only after in-flight
+	// requests have finished, so their visits are still drained and flushed.
+	s.handler.Recorder.Start(context.Background())
+	go s.handler.sweepExpiredLinks(ctx)
+
+	errCh := make(chan error, 1)
+	go func() {
+		if err := s.http.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			errCh <- err
+			return
+		}
+		errCh <- nil
+	}()
+
+	select {
+	case <-ctx.Done():
+		return s.Shutdown(context.Background())
+	case err := <-errCh:
+		return err
+	}
+}
+
+// Shutdown stops accepting new connections, waits up to the server's grace
+// period for in-flight requests to finish, flushes any visits still queued
+// in the VisitRecorder, then closes the pool.
+func (s *Server) Shutdown(ctx context.Context) error {
+	shutdownCtx, cancel := context.WithTimeout(ctx, s.cfg.GracePeriod)
+	defer cancel()
+
+	err := s.http.Shutdown(shutdownCtx)
+
+	s.handler.Recorder.Stop()
+	s.pool.Close()
+
+	return err
+}
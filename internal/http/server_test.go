@@ -0,0 +1,95 @@
+package httpapi
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestServerShutdownFlushesQueuedVisits(t *testing.T) {
+	sqlDB := openSQL(t)
+	defer func() {
+		if err := sqlDB.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	truncateAll(t, sqlDB)
+	_ = seedLink(t, sqlDB, "https://example.com/long-url", "exmpl")
+
+	pool := openPool(t)
+	srv := NewServer(pool, ServerConfig{
+		Addr:        "127.0.0.1:18080",
+		BaseURL:     "https://short.io",
+		GracePeriod: 5 * time.Second,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	runDone := make(chan error, 1)
+	go func() { runDone <- srv.Run(ctx) }()
+
+	if !waitForListener(t, "http://127.0.0.1:18080/ping") {
+		t.Fatal("server did not come up in time")
+	}
+
+	resp, err := http.Get("http://127.0.0.1:18080/r/exmpl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+
+	// Cancel immediately: the visit is still sitting in the VisitRecorder's
+	// buffer, not yet flushed on its own timer. Shutdown must flush it
+	// before returning.
+	cancel()
+
+	select {
+	case err := <-runDone:
+		if err != nil {
+			t.Fatalf("Run returned error: %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Run did not return after shutdown")
+	}
+
+	verifyDB, closePool := openVerifyPool(t)
+	defer closePool()
+
+	var count int
+	if err := verifyDB.QueryRow(`SELECT count(*) FROM link_visits`).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Fatalf("expected 1 flushed visit, got %d", count)
+	}
+}
+
+func waitForListener(t *testing.T, url string) bool {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if resp, err := http.Get(url); err == nil {
+			_ = resp.Body.Close()
+			return true
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	return false
+}
+
+// openVerifyPool opens a second connection independent of the Server's own
+// pool, since Shutdown closes that one.
+func openVerifyPool(t *testing.T) (*sql.DB, func()) {
+	t.Helper()
+
+	dsn := mustDSN(t)
+	sqlDB, err := sql.Open("pgx", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sqlDB, func() { _ = sqlDB.Close() }
+}
@@ -0,0 +1,216 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	db "shorty/internal/db/sqlc"
+)
+
+const (
+	sseSubscriberBuffer  = 32
+	sseHeartbeatInterval = 15 * time.Second
+)
+
+type visitSubscriber struct {
+	linkID int64 // 0 means "all links" (within allowedLinks, if set)
+	ch     chan linkVisitOut
+
+	// allowedLinks restricts an "all links" subscription to a fixed set of
+	// link ids -- the caller's own links, snapshotted at subscribe time.
+	// nil means unrestricted (admins watching every link).
+	allowedLinks map[int64]struct{}
+}
+
+// visitBroadcaster fans newly-persisted visits out to SSE subscribers,
+// optionally filtered to a single link or to a caller's own links. Each
+// subscriber has a bounded buffer; a subscriber that falls behind has new
+// events dropped for it rather than stalling the broadcaster for everyone
+// else.
+type visitBroadcaster struct {
+	mu   sync.Mutex
+	subs map[*visitSubscriber]struct{}
+}
+
+func newVisitBroadcaster() *visitBroadcaster {
+	return &visitBroadcaster{subs: make(map[*visitSubscriber]struct{})}
+}
+
+func (b *visitBroadcaster) subscribe(linkID int64, allowedLinks map[int64]struct{}) *visitSubscriber {
+	sub := &visitSubscriber{linkID: linkID, ch: make(chan linkVisitOut, sseSubscriberBuffer), allowedLinks: allowedLinks}
+	b.mu.Lock()
+	b.subs[sub] = struct{}{}
+	b.mu.Unlock()
+	return sub
+}
+
+func (b *visitBroadcaster) unsubscribe(sub *visitSubscriber) {
+	b.mu.Lock()
+	delete(b.subs, sub)
+	b.mu.Unlock()
+}
+
+func (b *visitBroadcaster) publish(v linkVisitOut) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for sub := range b.subs {
+		if sub.linkID != 0 && sub.linkID != v.LinkID {
+			continue
+		}
+		if sub.allowedLinks != nil {
+			if _, ok := sub.allowedLinks[v.LinkID]; !ok {
+				continue
+			}
+		}
+		select {
+		case sub.ch <- v:
+		default:
+		}
+	}
+}
+
+func visitOutFromRow(lv db.LinkVisit) linkVisitOut {
+	return linkVisitOut{
+		ID:             lv.ID,
+		LinkID:         lv.LinkID,
+		CreatedAt:      lv.CreatedAt.Time.UTC(),
+		IP:             lv.Ip,
+		UserAgent:      lv.UserAgent,
+		Status:         lv.Status,
+		Browser:        lv.Browser,
+		BrowserVersion: lv.BrowserVersion,
+		OS:             lv.Os,
+		DeviceType:     lv.DeviceType,
+		IsBot:          lv.IsBot,
+	}
+}
+
+// streamLinkVisits upgrades to an SSE connection and pushes newly persisted
+// link_visits as the VisitRecorder flushes them. ?link_id= scopes the
+// stream to a single link, which must be owned by the caller (or any link,
+// for an admin); omitting it streams every link the caller owns (every
+// link, for an admin). A Last-Event-ID header (or ?last_event_id=) naming a
+// previously seen visit id replays anything persisted since, straight from
+// the DB, before the connection starts receiving live events.
+func (h *Handler) streamLinkVisits(c *gin.Context) {
+	user := currentUser(c)
+
+	var linkID int64
+	if raw := c.Query("link_id"); raw != "" {
+		id, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || id <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid link_id"})
+			return
+		}
+		if _, ok := h.statsLink(c, id); !ok {
+			return
+		}
+		linkID = id
+	}
+
+	var allowedLinks map[int64]struct{}
+	if linkID == 0 && !user.IsAdmin {
+		ids, err := h.Q.ListLinkIDsByOwner(c.Request.Context(), user.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+			return
+		}
+		allowedLinks = make(map[int64]struct{}, len(ids))
+		for _, id := range ids {
+			allowedLinks[id] = struct{}{}
+		}
+	}
+
+	lastEventID := strings.TrimSpace(c.GetHeader("Last-Event-ID"))
+	if lastEventID == "" {
+		lastEventID = strings.TrimSpace(c.Query("last_event_id"))
+	}
+
+	// Subscribe before replaying so nothing published during the replay
+	// query is missed.
+	sub := h.Broadcaster.subscribe(linkID, allowedLinks)
+	defer h.Broadcaster.unsubscribe(sub)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	if lastEventID != "" {
+		if afterID, err := strconv.ParseInt(lastEventID, 10, 64); err == nil {
+			for _, v := range h.missedVisits(c, user, linkID, afterID) {
+				writeVisitEvent(c.Writer, v)
+			}
+			c.Writer.Flush()
+		}
+	}
+
+	heartbeat := time.NewTicker(sseHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	ctx := c.Request.Context()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case v := <-sub.ch:
+			writeVisitEvent(w, v)
+			return true
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	})
+}
+
+// missedVisits replays link_visits persisted after afterID for the Last-
+// Event-ID resume path. linkID, if non-zero, has already been checked for
+// ownership by the caller; the "all links" case (linkID == 0) is scoped to
+// user's own links unless user is an admin.
+func (h *Handler) missedVisits(c *gin.Context, user authUser, linkID, afterID int64) []linkVisitOut {
+	ctx := c.Request.Context()
+
+	var rows []db.LinkVisit
+	var err error
+	switch {
+	case linkID != 0:
+		rows, err = h.Q.ListLinkVisitsAfterForLink(ctx, db.ListLinkVisitsAfterForLinkParams{
+			LinkID:  linkID,
+			AfterID: afterID,
+		})
+	case user.IsAdmin:
+		rows, err = h.Q.ListLinkVisitsAfter(ctx, afterID)
+	default:
+		rows, err = h.Q.ListLinkVisitsAfterByOwner(ctx, db.ListLinkVisitsAfterByOwnerParams{
+			OwnerID: user.ID,
+			AfterID: afterID,
+		})
+	}
+	if err != nil {
+		return nil
+	}
+
+	out := make([]linkVisitOut, 0, len(rows))
+	for _, r := range rows {
+		out = append(out, visitOutFromRow(r))
+	}
+	return out
+}
+
+func writeVisitEvent(w io.Writer, v linkVisitOut) {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(w, "id: %d\nevent: visit\ndata: %s\n\n", v.ID, payload)
+}
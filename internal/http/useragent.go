@@ -0,0 +1,90 @@
+package httpapi
+
+import (
+	"regexp"
+	"strings"
+)
+
+// uaInfo is the result of classifying a User-Agent string, in the spirit of
+// uasurfer: enough to drive browser/OS/device breakdowns without pulling in
+// a full parsing dependency.
+type uaInfo struct {
+	Browser        string
+	BrowserVersion string
+	OS             string
+	DeviceType     string
+	IsBot          bool
+}
+
+var botRe = regexp.MustCompile(`(?i)bot|crawl|spider|slurp|mediapartners|facebookexternalhit|bingpreview|pingdom|uptimerobot|curl|wget|python-requests|go-http-client`)
+
+var browserRes = []struct {
+	name string
+	re   *regexp.Regexp
+}{
+	{"Edge", regexp.MustCompile(`Edg(?:A|iOS)?/([\d.]+)`)},
+	{"Opera", regexp.MustCompile(`(?:OPR|Opera)/([\d.]+)`)},
+	{"Chrome", regexp.MustCompile(`Chrome/([\d.]+)`)},
+	{"Firefox", regexp.MustCompile(`Firefox/([\d.]+)`)},
+	{"Safari", regexp.MustCompile(`Version/([\d.]+).*Safari`)},
+	{"IE", regexp.MustCompile(`MSIE ([\d.]+)`)},
+}
+
+var osRes = []struct {
+	name string
+	re   *regexp.Regexp
+}{
+	{"iOS", regexp.MustCompile(`iPhone|iPad|iPod`)},
+	{"Android", regexp.MustCompile(`Android`)},
+	{"Windows", regexp.MustCompile(`Windows NT`)},
+	{"macOS", regexp.MustCompile(`Mac OS X`)},
+	{"Linux", regexp.MustCompile(`Linux`)},
+}
+
+// parseUserAgent classifies a raw User-Agent header into browser, OS, device
+// type, and bot/human. Detection is best-effort string matching, not a
+// byte-for-byte UA database. An unrecognized browser or OS comes back as
+// "unknown" (with an empty BrowserVersion), and bots get DeviceType "bot".
+func parseUserAgent(ua string) uaInfo {
+	info := uaInfo{Browser: "unknown", OS: "unknown", DeviceType: "desktop"}
+
+	if strings.TrimSpace(ua) == "" {
+		info.IsBot = true
+		info.DeviceType = "bot"
+		return info
+	}
+
+	if botRe.MatchString(ua) {
+		info.IsBot = true
+	}
+
+	for _, b := range browserRes {
+		if m := b.re.FindStringSubmatch(ua); m != nil {
+			info.Browser = b.name
+			info.BrowserVersion = m[1]
+			break
+		}
+	}
+
+	for _, o := range osRes {
+		if o.re.MatchString(ua) {
+			info.OS = o.name
+			break
+		}
+	}
+
+	switch {
+	case strings.Contains(ua, "iPad") || strings.Contains(ua, "Tablet") || (strings.Contains(ua, "Android") && !strings.Contains(ua, "Mobile")):
+		info.DeviceType = "tablet"
+	case strings.Contains(ua, "Mobile") || strings.Contains(ua, "iPhone") || strings.Contains(ua, "Android"):
+		info.DeviceType = "mobile"
+	default:
+		info.DeviceType = "desktop"
+	}
+
+	if info.IsBot {
+		info.DeviceType = "bot"
+	}
+
+	return info
+}
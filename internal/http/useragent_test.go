@@ -0,0 +1,41 @@
+package httpapi
+
+import "testing"
+
+func TestParseUserAgent(t *testing.T) {
+	cases := []struct {
+		name string
+		ua   string
+		want uaInfo
+	}{
+		{
+			name: "chrome on windows",
+			ua:   "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/115.0.0.0 Safari/537.36",
+			want: uaInfo{Browser: "Chrome", BrowserVersion: "115.0.0.0", OS: "Windows", DeviceType: "desktop", IsBot: false},
+		},
+		{
+			name: "safari on iphone",
+			ua:   "Mozilla/5.0 (iPhone; CPU iPhone OS 16_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.5 Mobile/15E148 Safari/604.1",
+			want: uaInfo{Browser: "Safari", BrowserVersion: "16.5", OS: "iOS", DeviceType: "mobile", IsBot: false},
+		},
+		{
+			name: "googlebot",
+			ua:   "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+			want: uaInfo{Browser: "unknown", BrowserVersion: "", OS: "unknown", DeviceType: "bot", IsBot: true},
+		},
+		{
+			name: "empty",
+			ua:   "",
+			want: uaInfo{Browser: "unknown", BrowserVersion: "", OS: "unknown", DeviceType: "bot", IsBot: true},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := parseUserAgent(tc.ua)
+			if got != tc.want {
+				t.Fatalf("parseUserAgent(%q) = %+v, want %+v", tc.ua, got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,248 @@
+package httpapi
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	db "shorty/internal/db/sqlc"
+)
+
+// OverflowPolicy controls what VisitRecorder.Enqueue does when the buffer is
+// full.
+type OverflowPolicy int
+
+const (
+	// OverflowDropOldest discards the oldest buffered visit to make room for
+	// the new one, so Enqueue never blocks the redirect path.
+	OverflowDropOldest OverflowPolicy = iota
+	// OverflowBlock applies backpressure to the caller instead of dropping
+	// visits.
+	OverflowBlock
+)
+
+type VisitRecorderConfig struct {
+	BufferSize    int
+	BatchSize     int
+	FlushInterval time.Duration
+	Overflow      OverflowPolicy
+}
+
+func DefaultVisitRecorderConfig() VisitRecorderConfig {
+	return VisitRecorderConfig{
+		BufferSize:    1024,
+		BatchSize:     100,
+		FlushInterval: 200 * time.Millisecond,
+		Overflow:      OverflowDropOldest,
+	}
+}
+
+// VisitRecorder decouples redirect latency from the link_visits insert: the
+// redirect handler enqueues visits in memory and a background worker
+// flushes them to Postgres in batches via a multi-row INSERT, so a slow
+// write doesn't slow down the user's redirect.
+type VisitRecorder struct {
+	pool *pgxpool.Pool
+	cfg  VisitRecorderConfig
+
+	ch   chan db.CreateLinkVisitParams
+	done chan struct{}
+	wg   sync.WaitGroup
+
+	// OnFlush, if set, is called with the rows a batch insert just
+	// persisted (in the order submitted), id and created_at now populated.
+	// Used to fan visits out to SSE subscribers as they land.
+	OnFlush func([]db.LinkVisit)
+
+	Queued  atomic.Int64
+	Dropped atomic.Int64
+	Flushed atomic.Int64
+}
+
+func NewVisitRecorder(pool *pgxpool.Pool, cfg VisitRecorderConfig) *VisitRecorder {
+	return &VisitRecorder{
+		pool: pool,
+		cfg:  cfg,
+		ch:   make(chan db.CreateLinkVisitParams, cfg.BufferSize),
+		done: make(chan struct{}),
+	}
+}
+
+// Start launches the background flush loop. It returns immediately; call
+// Stop, or cancel ctx, to drain the buffer and stop it.
+func (r *VisitRecorder) Start(ctx context.Context) {
+	r.wg.Add(1)
+	go r.run(ctx)
+}
+
+// Stop signals the flush loop to drain whatever is buffered and exit,
+// blocking until it has.
+func (r *VisitRecorder) Stop() {
+	close(r.done)
+	r.wg.Wait()
+}
+
+// Enqueue submits a visit for async insertion. Under OverflowDropOldest it
+// never blocks the caller; under OverflowBlock it applies backpressure to
+// the redirect path once the buffer is full.
+func (r *VisitRecorder) Enqueue(v db.CreateLinkVisitParams) {
+	if r.cfg.Overflow == OverflowBlock {
+		r.ch <- v
+		r.Queued.Add(1)
+		return
+	}
+
+	select {
+	case r.ch <- v:
+		r.Queued.Add(1)
+		return
+	default:
+	}
+
+	select {
+	case <-r.ch:
+		r.Dropped.Add(1)
+	default:
+	}
+
+	select {
+	case r.ch <- v:
+		r.Queued.Add(1)
+	default:
+		r.Dropped.Add(1)
+	}
+}
+
+func (r *VisitRecorder) run(ctx context.Context) {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(r.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]db.CreateLinkVisitParams, 0, r.cfg.BatchSize)
+
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		inserted, err := r.insertBatch(ctx, batch)
+		if err == nil {
+			r.Flushed.Add(int64(len(inserted)))
+			if r.OnFlush != nil {
+				r.OnFlush(inserted)
+			}
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case v := <-r.ch:
+			batch = append(batch, v)
+			if len(batch) >= r.cfg.BatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-r.done:
+			r.drain(&batch)
+			r.terminalFlush(&batch)
+			return
+		case <-ctx.Done():
+			r.drain(&batch)
+			r.terminalFlush(&batch)
+			return
+		}
+	}
+}
+
+// terminalFlushTimeout bounds the final drain flush issued when the recorder
+// is stopping, so it doesn't hang forever if Postgres is unreachable.
+const terminalFlushTimeout = 5 * time.Second
+
+// terminalFlush persists whatever is left in batch using a fresh, independent
+// context rather than the ctx passed to Start: when run is stopping because
+// that ctx was cancelled, insertBatch under it would fail immediately without
+// ever issuing the INSERT.
+func (r *VisitRecorder) terminalFlush(batch *[]db.CreateLinkVisitParams) {
+	if len(*batch) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), terminalFlushTimeout)
+	defer cancel()
+
+	inserted, err := r.insertBatch(ctx, *batch)
+	if err == nil {
+		r.Flushed.Add(int64(len(inserted)))
+		if r.OnFlush != nil {
+			r.OnFlush(inserted)
+		}
+	}
+	*batch = (*batch)[:0]
+}
+
+// drain pulls any remaining buffered visits into batch without blocking, so
+// a stop/cancellation doesn't lose visits still sitting in the channel.
+func (r *VisitRecorder) drain(batch *[]db.CreateLinkVisitParams) {
+	for {
+		select {
+		case v := <-r.ch:
+			*batch = append(*batch, v)
+		default:
+			return
+		}
+	}
+}
+
+const visitInsertColumns = 10
+
+// insertBatch persists batch as a single multi-row INSERT and returns the
+// fully-populated rows (id, created_at included) in submission order, which
+// Postgres preserves for a plain INSERT ... VALUES ... RETURNING.
+func (r *VisitRecorder) insertBatch(ctx context.Context, batch []db.CreateLinkVisitParams) ([]db.LinkVisit, error) {
+	if len(batch) == 0 {
+		return nil, nil
+	}
+
+	placeholders := make([]string, 0, len(batch))
+	args := make([]any, 0, len(batch)*visitInsertColumns)
+	for i, v := range batch {
+		base := i * visitInsertColumns
+		ph := make([]string, visitInsertColumns)
+		for j := range ph {
+			ph[j] = fmt.Sprintf("$%d", base+j+1)
+		}
+		placeholders = append(placeholders, "("+strings.Join(ph, ",")+")")
+		args = append(args, v.LinkID, v.Ip, v.UserAgent, v.Referer, v.Status,
+			v.Browser, v.BrowserVersion, v.Os, v.DeviceType, v.IsBot)
+	}
+
+	query := `INSERT INTO link_visits
+		(link_id, ip, user_agent, referer, status, browser, browser_version, os, device_type, is_bot)
+		VALUES ` + strings.Join(placeholders, ",") + `
+		RETURNING id, link_id, created_at, ip, user_agent, referer, status, browser, browser_version, os, device_type, is_bot`
+
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	inserted := make([]db.LinkVisit, 0, len(batch))
+	for rows.Next() {
+		var lv db.LinkVisit
+		if err := rows.Scan(
+			&lv.ID, &lv.LinkID, &lv.CreatedAt, &lv.Ip, &lv.UserAgent, &lv.Referer, &lv.Status,
+			&lv.Browser, &lv.BrowserVersion, &lv.Os, &lv.DeviceType, &lv.IsBot,
+		); err != nil {
+			return nil, err
+		}
+		inserted = append(inserted, lv)
+	}
+	return inserted, rows.Err()
+}